@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor produces a compressed copy of a log file. Implementations let
+// callers plug in alternate codecs beyond the built-in gzip and zstd.
+type Compressor interface {
+	// Ext returns the suffix appended to a compressed file, e.g. ".gz".
+	Ext() string
+	// Compress reads src to completion and writes its compressed form to
+	// dst.
+	Compress(dst io.Writer, src io.Reader) error
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Ext() string { return ".gz" }
+
+func (gzipCompressor) Compress(dst io.Writer, src io.Reader) error {
+	w := gzip.NewWriter(dst)
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) Ext() string { return ".zst" }
+
+func (zstdCompressor) Compress(dst io.Writer, src io.Reader) error {
+	w, err := zstd.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// compressorFor resolves a Rule's codec name to a Compressor, defaulting to
+// gzip when unset.
+func compressorFor(codec string) (Compressor, error) {
+	switch codec {
+	case "", "gzip":
+		return gzipCompressor{}, nil
+	case "zstd":
+		return zstdCompressor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", codec)
+	}
+}
+
+// compressFile compresses c's file in place using codec: the compressed
+// artifact is written, fsynced and its mtime set to match the original so
+// mtime-based retention keeps working, and only then is the original
+// removed. The pre-compression timestamp rule ages c by is returned so the
+// caller can preserve it out of band: compressing a file necessarily
+// rewrites it, which bumps its ctime to "now", so ctime-based retention
+// can no longer be trusted to reflect it after this call.
+func compressFile(c candidate, root string, rule Rule, codec Compressor, l *log.Logger) (dstPath string, originalTimestamp time.Time, err error) {
+	if !confinedToRoot(root, c.path, rule.FollowSymlinks) {
+		return "", time.Time{}, fmt.Errorf("refusing to compress %s: escapes root %s", c.path, root)
+	}
+	originalTimestamp = fileTimestamp(c, rule, nil, l)
+
+	src, err := os.Open(c.path)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("opening %s: %w", c.path, err)
+	}
+	defer src.Close()
+
+	dstPath = c.path + codec.Ext()
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating %s: %w", dstPath, err)
+	}
+
+	if err := codec.Compress(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return "", time.Time{}, fmt.Errorf("compressing %s: %w", c.path, err)
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return "", time.Time{}, fmt.Errorf("fsyncing %s: %w", dstPath, err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dstPath)
+		return "", time.Time{}, fmt.Errorf("closing %s: %w", dstPath, err)
+	}
+
+	modTime := c.info.ModTime()
+	if err := os.Chtimes(dstPath, modTime, modTime); err != nil {
+		return "", time.Time{}, fmt.Errorf("preserving mtime on %s: %w", dstPath, err)
+	}
+
+	l.Printf("compressed %s to %s\n", c.path, dstPath)
+	if err := os.Remove(c.path); err != nil {
+		return "", time.Time{}, err
+	}
+	return dstPath, originalTimestamp, nil
+}
+
+// compressTier compresses every candidate due for compression under rule,
+// honoring rule.DryRun by only logging the intent. It returns the
+// pre-compression timestamps of whatever it compressed, keyed by the
+// resulting compressed path, for the caller to feed back into
+// selectForDeletion so ctime-based retention isn't reset by the rewrite.
+func compressTier(rule Rule, found []candidate, now time.Time, l *log.Logger) (timestampOverrides, error) {
+	compressor, err := compressorFor(rule.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("resolving codec for %s: %w", rule.Root, err)
+	}
+
+	toCompress := selectForCompression(found, rule, now, l)
+	if len(toCompress) == 0 {
+		return nil, nil
+	}
+	l.Printf("%d files will be compressed under %s\n", len(toCompress), rule.Root)
+
+	overrides := make(timestampOverrides, len(toCompress))
+	var errs []error
+	for _, c := range toCompress {
+		if rule.DryRun {
+			l.Printf("dry-run: would compress %s\n", c.path)
+			continue
+		}
+		dstPath, originalTimestamp, err := compressFile(c, rule.Root, rule, compressor, l)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		overrides[dstPath] = originalTimestamp
+	}
+	return overrides, errors.Join(errs...)
+}
+
+// selectForCompression returns candidates older than rule.CompressAfter but
+// not yet past rule.DeleteAfter, skipping anything already compressed.
+func selectForCompression(candidates []candidate, rule Rule, now time.Time, l *log.Logger) []candidate {
+	if rule.CompressAfter.Duration <= 0 {
+		return nil
+	}
+
+	var res []candidate
+	for _, c := range candidates {
+		if c.compressed {
+			continue
+		}
+		age := now.Sub(fileTimestamp(c, rule, nil, l))
+		if age <= rule.CompressAfter.Duration {
+			continue
+		}
+		if rule.DeleteAfter.Duration > 0 && age > rule.DeleteAfter.Duration {
+			continue
+		}
+		res = append(res, c)
+	}
+	return res
+}
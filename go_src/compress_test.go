@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressorFor(t *testing.T) {
+	cases := []struct {
+		name    string
+		codec   string
+		wantExt string
+		wantErr bool
+	}{
+		{name: "empty defaults to gzip", codec: "", wantExt: ".gz"},
+		{name: "gzip", codec: "gzip", wantExt: ".gz"},
+		{name: "zstd", codec: "zstd", wantExt: ".zst"},
+		{name: "unknown codec", codec: "lz4", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			compressor, err := compressorFor(c.codec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for codec %q", c.codec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compressorFor(%q): %v", c.codec, err)
+			}
+			if compressor.Ext() != c.wantExt {
+				t.Errorf("Ext() = %s, want %s", compressor.Ext(), c.wantExt)
+			}
+		})
+	}
+}
+
+func decompress(t *testing.T, codec, path string) []byte {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader
+	switch codec {
+	case "gzip", "":
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("creating gzip reader for %s: %v", path, err)
+		}
+		defer gr.Close()
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatalf("creating zstd reader for %s: %v", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		t.Fatalf("unhandled codec %q", codec)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decompressing %s: %v", path, err)
+	}
+	return got
+}
+
+func TestCompressFile_RoundTrip(t *testing.T) {
+	for _, codecName := range []string{"gzip", "zstd"} {
+		t.Run(codecName, func(t *testing.T) {
+			dir := t.TempDir()
+			mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			want := []byte("line one\nline two\n")
+
+			c := testCandidate(t, dir, "app.log", 0, mtime)
+			if err := os.WriteFile(c.path, want, 0o644); err != nil {
+				t.Fatalf("writing %s: %v", c.path, err)
+			}
+
+			codec, err := compressorFor(codecName)
+			if err != nil {
+				t.Fatalf("compressorFor(%s): %v", codecName, err)
+			}
+			rule := Rule{Codec: codecName}
+			l := discardLogger()
+
+			dstPath, originalTimestamp, err := compressFile(c, dir, rule, codec, l)
+			if err != nil {
+				t.Fatalf("compressFile: %v", err)
+			}
+
+			if !originalTimestamp.Equal(mtime) {
+				t.Errorf("originalTimestamp = %v, want %v", originalTimestamp, mtime)
+			}
+			if filepath.Ext(dstPath) != codec.Ext() {
+				t.Errorf("dstPath = %s, want suffix %s", dstPath, codec.Ext())
+			}
+			if _, err := os.Stat(c.path); !os.IsNotExist(err) {
+				t.Errorf("expected original %s to be removed, stat err = %v", c.path, err)
+			}
+
+			info, err := os.Stat(dstPath)
+			if err != nil {
+				t.Fatalf("stat %s: %v", dstPath, err)
+			}
+			if !info.ModTime().Equal(mtime) {
+				t.Errorf("compressed file mtime = %v, want %v", info.ModTime(), mtime)
+			}
+
+			got := decompress(t, codecName, dstPath)
+			if !bytes.Equal(got, want) {
+				t.Errorf("decompressed content = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RetentionMode selects which timestamp a rule ages files against.
+type RetentionMode string
+
+const (
+	// RetentionModeMTime ages files by their last-write time. This is the
+	// default and matches the original housekeeper's behavior.
+	RetentionModeMTime RetentionMode = "mtime"
+	// RetentionModeCTime ages files by inode change time, which is bumped
+	// by writes and also by chmod/rename.
+	RetentionModeCTime RetentionMode = "ctime"
+	// RetentionModeFilename ages files by a timestamp embedded in their
+	// name, falling back to mtime when the name doesn't parse.
+	RetentionModeFilename RetentionMode = "filename"
+)
+
+// Rule declares a single sweep target: a root directory plus the glob
+// filters and retention settings applied to everything found under it.
+type Rule struct {
+	Root        string   `yaml:"root"`
+	Include     []string `yaml:"include"`
+	Exclude     []string `yaml:"exclude"`
+	DeleteAfter Duration `yaml:"delete_after"`
+	// Owner restricts matches to files owned by the current user, mirroring
+	// the safety check the gclogs housekeeper applies before deleting.
+	Owner  bool `yaml:"owner"`
+	DryRun bool `yaml:"dry_run"`
+
+	// RetentionMode picks which timestamp ages a file. Defaults to mtime
+	// when left empty.
+	RetentionMode RetentionMode `yaml:"retention_mode"`
+	// FilenameRegexp extracts the timestamp substring from a file's name;
+	// it must contain exactly one capture group. Only used when
+	// RetentionMode is "filename".
+	FilenameRegexp string `yaml:"filename_regexp"`
+	// FilenameLayout is the reference-time layout (as in time.Parse) used
+	// to parse the substring FilenameRegexp captures, e.g. the
+	// "20060102-150405" layout for "program.host.user.log.INFO.20130806-151006.10530".
+	FilenameLayout string `yaml:"filename_layout"`
+
+	// MaxTotalBytes, if non-zero, deletes the oldest matching files first
+	// until the remaining set is under this size.
+	MaxTotalBytes int64 `yaml:"max_total_bytes"`
+	// MaxFiles, if non-zero, keeps only the newest N matching files and
+	// deletes the rest.
+	MaxFiles int `yaml:"max_files"`
+
+	// CompressAfter, if non-zero, compresses files older than this (but
+	// younger than DeleteAfter) in place instead of leaving them as-is.
+	CompressAfter Duration `yaml:"compress_after"`
+	// Codec selects the compressor used for CompressAfter: "gzip" (the
+	// default) or "zstd".
+	Codec string `yaml:"codec"`
+
+	// FollowSymlinks allows the walk to descend into symlinked directories
+	// and act on symlinked files. Defaults to false: symlinked directories
+	// are skipped and symlinked files are left alone.
+	FollowSymlinks bool `yaml:"follow_symlinks"`
+}
+
+// Config is the top-level shape of the housekeeping config file: a list of
+// independent sweep rules, each processed on its own.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+	// Workers bounds how many files are deleted concurrently per rule.
+	// Defaults to defaultWorkers when left at zero.
+	Workers int `yaml:"workers"`
+
+	// AllowedRoots is the set of directories a rule's Root must resolve
+	// under. Leaving this empty does not relax the other safety checks in
+	// resolveRoot, but it does skip the containment check itself.
+	AllowedRoots []string `yaml:"allowed_roots"`
+	// MinRootDepth is the shallowest a resolved root may be. Defaults to
+	// defaultMinRootDepth when left at zero.
+	MinRootDepth int `yaml:"min_root_depth"`
+}
+
+// Duration wraps time.Duration so config values like "4d" or "12h" can be
+// written directly in YAML instead of as raw nanosecond integers. "d" is a
+// day unit time.ParseDuration doesn't understand on its own; UnmarshalYAML
+// expands it to hours before delegating.
+type Duration struct {
+	time.Duration
+}
+
+// dayUnitPattern matches a day component (e.g. "4d" in "4d12h") so it can
+// be rewritten to hours for time.ParseDuration.
+var dayUnitPattern = regexp.MustCompile(`(\d+)d`)
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	expanded := dayUnitPattern.ReplaceAllStringFunc(s, func(m string) string {
+		days, err := strconv.Atoi(m[:len(m)-1])
+		if err != nil {
+			return m
+		}
+		return fmt.Sprintf("%dh", days*24)
+	})
+
+	parsed, err := time.ParseDuration(expanded)
+	if err != nil {
+		return fmt.Errorf("parsing duration %q: %w", s, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// LoadConfig reads and parses a housekeeping config file from path.
+func LoadConfig(path string) (Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDuration_UnmarshalYAML(t *testing.T) {
+	cases := []struct {
+		name    string
+		yaml    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "plain hours", yaml: "12h", want: 12 * time.Hour},
+		{name: "single day unit", yaml: "4d", want: 4 * 24 * time.Hour},
+		{name: "day unit combined with hours", yaml: "4d12h", want: 4*24*time.Hour + 12*time.Hour},
+		{name: "multiple day units", yaml: "1d2d", want: 3 * 24 * time.Hour},
+		{name: "zero", yaml: "0h", want: 0},
+		{name: "invalid unit", yaml: "4x", wantErr: true},
+		{name: "not a duration at all", yaml: "soon", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var d Duration
+			err := yaml.Unmarshal([]byte(c.yaml), &d)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error unmarshaling %q", c.yaml)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshaling %q: %v", c.yaml, err)
+			}
+			if d.Duration != c.want {
+				t.Errorf("Duration for %q = %v, want %v", c.yaml, d.Duration, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// compressedExtensions lists the suffixes compressFile can produce. A file
+// ending in one of these is the already-compressed form of a logical log
+// that matched a rule's patterns before compression.
+var compressedExtensions = []string{".gz", ".zst"}
+
+// candidate is a file found while walking a rule's root, along with the
+// stat info needed to decide whether it matches the rule's filters.
+type candidate struct {
+	path string
+	info fs.FileInfo
+	// compressed is true when path is the already-compressed form of a
+	// logical log (e.g. "app.log.gz" matching the "**/*.log" include
+	// pattern via its "app.log" stem), so it's never compressed again.
+	compressed bool
+}
+
+// findLogFiles walks rule.Root and returns every file matching
+// rule.Include (and none of rule.Exclude), evaluated as paths relative to
+// the root so patterns like "**/*.log" behave as expected. Unlike
+// filepath.WalkDir, this recurses into symlinked directories itself when
+// rule.FollowSymlinks is set: WalkDir never reports a symlink's DirEntry
+// as a directory, even when it points at one, so it can't do this on its
+// own.
+func findLogFiles(rule Rule, l *log.Logger) ([]candidate, error) {
+	var res []candidate
+	ignores := map[string][]string{}
+	visited := map[string]bool{}
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if visited[real] {
+				l.Printf("skipping %s: symlink cycle detected\n", dir)
+				return
+			}
+			visited[real] = true
+		}
+
+		patterns, err := loadIgnorePatterns(dir)
+		if err != nil {
+			l.Printf("error reading .housekeepignore in %s: %v\n", dir, err)
+		} else if len(patterns) > 0 {
+			ignores[dir] = patterns
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			l.Printf("error reading %s: %v\n", dir, err)
+			return
+		}
+
+		for _, e := range entries {
+			p := filepath.Join(dir, e.Name())
+			isSymlink := e.Type()&fs.ModeSymlink != 0
+
+			if isSymlink && !rule.FollowSymlinks {
+				l.Printf("skipping symlink %s (follow_symlinks disabled)\n", p)
+				continue
+			}
+
+			isDir := e.IsDir()
+			if isSymlink {
+				target, err := os.Stat(p)
+				if err != nil {
+					l.Printf("error resolving symlink %s: %v\n", p, err)
+					continue
+				}
+				isDir = target.IsDir()
+			}
+
+			if isDir {
+				walk(p)
+				continue
+			}
+
+			if ignoredByAncestors(p, ignores, rule.Root) {
+				continue
+			}
+
+			rel, err := filepath.Rel(rule.Root, p)
+			if err != nil {
+				l.Printf("error computing relative path for %s: %v\n", p, err)
+				continue
+			}
+
+			matched, compressed := matchesRule(rule, rel)
+			if !matched {
+				continue
+			}
+
+			var info fs.FileInfo
+			if isSymlink {
+				info, err = os.Stat(p)
+			} else {
+				info, err = e.Info()
+			}
+			if err != nil {
+				l.Printf("error fetching file info for %s: %v\n", p, err)
+				continue
+			}
+
+			if rule.Owner && !ownedByCurrentUser(info) {
+				continue
+			}
+
+			if !confinedToRoot(rule.Root, p, rule.FollowSymlinks) {
+				l.Printf("skipping %s: resolves outside root %s\n", p, rule.Root)
+				continue
+			}
+
+			res = append(res, candidate{path: p, info: info, compressed: compressed})
+		}
+	}
+
+	walk(rule.Root)
+	return res, nil
+}
+
+// matchesRule reports whether rel matches rule's include/exclude patterns,
+// either directly or as the compressed form of a logical log that does.
+// The second return value is true when the match was only via a stripped
+// compressed-extension stem, so callers can skip re-compressing it.
+func matchesRule(rule Rule, rel string) (matched, compressed bool) {
+	if matchesAny(rule.Include, rel) && !excluded(rule.Exclude, rel) {
+		return true, false
+	}
+	for _, ext := range compressedExtensions {
+		stem, ok := strings.CutSuffix(rel, ext)
+		if !ok {
+			continue
+		}
+		if matchesAny(rule.Include, stem) && !excluded(rule.Exclude, stem) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// excluded reports whether rel matches one of the given doublestar
+// patterns. Unlike matchesAny, an empty pattern list matches nothing: a
+// rule with no Exclude patterns excludes no files.
+func excluded(patterns []string, rel string) bool {
+	return len(patterns) > 0 && matchesAny(patterns, rel)
+}
+
+// matchesAny reports whether rel matches any of the given doublestar
+// patterns. An empty pattern list matches everything, so Include can be
+// left unset to mean "all files".
+func matchesAny(patterns []string, rel string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pat := range patterns {
+		if ok, _ := doublestar.Match(pat, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// selectForDeletion filters candidates down to those older than rule.DeleteAfter,
+// aged per rule.RetentionMode, plus whatever enforceQuotas adds on top to
+// satisfy MaxTotalBytes/MaxFiles.
+func selectForDeletion(candidates []candidate, rule Rule, now time.Time, overrides timestampOverrides, l *log.Logger) []candidate {
+	selected := make(map[string]candidate)
+	for _, c := range candidates {
+		if now.Sub(fileTimestamp(c, rule, overrides, l)) > rule.DeleteAfter.Duration {
+			selected[c.path] = c
+		}
+	}
+	for _, c := range enforceQuotas(candidates, rule, overrides, l) {
+		selected[c.path] = c
+	}
+
+	res := make([]candidate, 0, len(selected))
+	for _, c := range selected {
+		res = append(res, c)
+	}
+	return res
+}
+
+// enforceQuotas returns the oldest candidates that must be deleted to bring
+// the set under rule.MaxFiles and rule.MaxTotalBytes. Either cap left at
+// zero is not enforced.
+func enforceQuotas(candidates []candidate, rule Rule, overrides timestampOverrides, l *log.Logger) []candidate {
+	if rule.MaxFiles <= 0 && rule.MaxTotalBytes <= 0 {
+		return nil
+	}
+
+	byAge := make([]candidate, len(candidates))
+	copy(byAge, candidates)
+	sort.Slice(byAge, func(i, j int) bool {
+		return fileTimestamp(byAge[i], rule, overrides, l).Before(fileTimestamp(byAge[j], rule, overrides, l))
+	})
+
+	toDelete := make(map[string]candidate)
+
+	if rule.MaxFiles > 0 && len(byAge) > rule.MaxFiles {
+		for _, c := range byAge[:len(byAge)-rule.MaxFiles] {
+			toDelete[c.path] = c
+		}
+	}
+
+	if rule.MaxTotalBytes > 0 {
+		var total int64
+		for _, c := range byAge {
+			total += c.info.Size()
+		}
+		for _, c := range byAge {
+			if total <= rule.MaxTotalBytes {
+				break
+			}
+			toDelete[c.path] = c
+			total -= c.info.Size()
+		}
+	}
+
+	res := make([]candidate, 0, len(toDelete))
+	for _, c := range toDelete {
+		res = append(res, c)
+	}
+	return res
+}
+
+// removeFile deletes path, or just logs the intent when dryRun is set. It
+// re-checks containment against root immediately before removing, since a
+// symlink swapped in between the walk and the delete could otherwise
+// redirect the removal outside the intended tree. followSymlinks must match
+// the flag the walk used to reach path.
+func removeFile(path, root string, dryRun, followSymlinks bool, l *log.Logger) error {
+	if !confinedToRoot(root, path, followSymlinks) {
+		return fmt.Errorf("refusing to delete %s: escapes root %s", path, root)
+	}
+	if dryRun {
+		l.Printf("dry-run: would delete %s\n", path)
+		return nil
+	}
+	l.Printf("deleting %s\n", path)
+	return os.Remove(path)
+}
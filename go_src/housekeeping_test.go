@@ -0,0 +1,261 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCandidate creates a file of the given size under dir with the given
+// mtime and returns the resulting candidate.
+func testCandidate(t *testing.T, dir, name string, size int, mtime time.Time) candidate {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("setting mtime on %s: %v", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat %s: %v", path, err)
+	}
+	return candidate{path: path, info: info}
+}
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestEnforceQuotas_MaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	l := discardLogger()
+
+	var candidates []candidate
+	for i := 0; i < 5; i++ {
+		candidates = append(candidates, testCandidate(t, dir, filename(i), 10, now.Add(-time.Duration(5-i)*time.Hour)))
+	}
+
+	rule := Rule{MaxFiles: 3}
+	toDelete := enforceQuotas(candidates, rule, nil, l)
+
+	if len(toDelete) != 2 {
+		t.Fatalf("expected 2 files over quota, got %d", len(toDelete))
+	}
+	wantOldest := map[string]bool{candidates[0].path: true, candidates[1].path: true}
+	for _, c := range toDelete {
+		if !wantOldest[c.path] {
+			t.Errorf("unexpected file selected for deletion: %s", c.path)
+		}
+	}
+}
+
+func TestEnforceQuotas_MaxTotalBytes(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	l := discardLogger()
+
+	// Oldest to newest, 10 bytes each, total 50 bytes.
+	var candidates []candidate
+	for i := 0; i < 5; i++ {
+		candidates = append(candidates, testCandidate(t, dir, filename(i), 10, now.Add(-time.Duration(5-i)*time.Hour)))
+	}
+
+	rule := Rule{MaxTotalBytes: 25}
+	toDelete := enforceQuotas(candidates, rule, nil, l)
+
+	// Must shed the 3 oldest (30 bytes) to get under 25.
+	if len(toDelete) != 3 {
+		t.Fatalf("expected 3 files evicted to satisfy byte cap, got %d", len(toDelete))
+	}
+	for i := 0; i < 3; i++ {
+		found := false
+		for _, c := range toDelete {
+			if c.path == candidates[i].path {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s to be evicted", candidates[i].path)
+		}
+	}
+}
+
+func TestEnforceQuotas_NoCaps(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	l := discardLogger()
+
+	candidates := []candidate{testCandidate(t, dir, "a.log", 10, now)}
+	if got := enforceQuotas(candidates, Rule{}, nil, l); got != nil {
+		t.Fatalf("expected no evictions with no caps set, got %v", got)
+	}
+}
+
+func TestSelectForDeletion_AgeAndQuotaUnion(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	l := discardLogger()
+
+	// a.log is old enough to be deleted by age alone.
+	a := testCandidate(t, dir, "a.log", 10, now.Add(-48*time.Hour))
+	// b.log and c.log are fresh; MaxFiles keeps only the newest (c.log), so
+	// b.log is pushed out.
+	b := testCandidate(t, dir, "b.log", 10, now.Add(-2*time.Hour))
+	c := testCandidate(t, dir, "c.log", 10, now.Add(-1*time.Hour))
+
+	rule := Rule{DeleteAfter: Duration{24 * time.Hour}, MaxFiles: 1}
+	toDelete := selectForDeletion([]candidate{a, b, c}, rule, now, nil, l)
+
+	want := map[string]bool{a.path: true, b.path: true}
+	if len(toDelete) != len(want) {
+		t.Fatalf("expected %d files selected, got %d", len(want), len(toDelete))
+	}
+	for _, got := range toDelete {
+		if !want[got.path] {
+			t.Errorf("unexpected file selected for deletion: %s", got.path)
+		}
+	}
+}
+
+func filename(i int) string {
+	return string(rune('a'+i)) + ".log"
+}
+
+func TestFindLogFiles_SymlinkedDirectory(t *testing.T) {
+	root := t.TempDir()
+	target := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(target, "behind-symlink.log"), nil, 0o644); err != nil {
+		t.Fatalf("writing target file: %v", err)
+	}
+	if err := os.Symlink(target, filepath.Join(root, "linked")); err != nil {
+		t.Fatalf("creating symlinked directory: %v", err)
+	}
+
+	rule := Rule{Root: root, Include: []string{"**/*.log"}}
+	l := discardLogger()
+
+	found, err := findLogFiles(rule, l)
+	if err != nil {
+		t.Fatalf("findLogFiles: %v", err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("expected symlinked directory to be skipped by default, found %v", found)
+	}
+
+	rule.FollowSymlinks = true
+	found, err = findLogFiles(rule, l)
+	if err != nil {
+		t.Fatalf("findLogFiles: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 file behind the symlinked directory, found %d: %v", len(found), found)
+	}
+	want := filepath.Join(root, "linked", "behind-symlink.log")
+	if found[0].path != want {
+		t.Errorf("found path = %s, want %s", found[0].path, want)
+	}
+}
+
+func TestMatchesRule(t *testing.T) {
+	cases := []struct {
+		name       string
+		rule       Rule
+		rel        string
+		matched    bool
+		compressed bool
+	}{
+		{
+			name:    "no filters matches everything",
+			rule:    Rule{},
+			rel:     "app.log",
+			matched: true,
+		},
+		{
+			name:    "include glob matches",
+			rule:    Rule{Include: []string{"**/*.log"}},
+			rel:     "sub/app.log",
+			matched: true,
+		},
+		{
+			name:    "include glob does not match",
+			rule:    Rule{Include: []string{"**/*.log"}},
+			rel:     "app.txt",
+			matched: false,
+		},
+		{
+			name:    "exclude removes an otherwise-matching file",
+			rule:    Rule{Include: []string{"**/*.log"}, Exclude: []string{"**/debug.log"}},
+			rel:     "debug.log",
+			matched: false,
+		},
+		{
+			name:    "unset exclude excludes nothing",
+			rule:    Rule{Include: []string{"**/*.log"}},
+			rel:     "debug.log",
+			matched: true,
+		},
+		{
+			name:       "compressed stem matches via its gzip form",
+			rule:       Rule{Include: []string{"**/*.log"}},
+			rel:        "app.log.gz",
+			matched:    true,
+			compressed: true,
+		},
+		{
+			name:       "compressed stem matches via its zstd form",
+			rule:       Rule{Include: []string{"**/*.log"}},
+			rel:        "app.log.zst",
+			matched:    true,
+			compressed: true,
+		},
+		{
+			name: "compressed stem still honors exclude",
+			rule: Rule{Include: []string{"**/*.log"}, Exclude: []string{"**/debug.log"}},
+			rel:  "debug.log.gz",
+		},
+		{
+			name: "bare gzip file with no matching stem does not match",
+			rule: Rule{Include: []string{"**/*.log"}},
+			rel:  "archive.tar.gz",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matched, compressed := matchesRule(c.rule, c.rel)
+			if matched != c.matched || compressed != c.compressed {
+				t.Errorf("matchesRule(%s) = (%v, %v), want (%v, %v)", c.rel, matched, compressed, c.matched, c.compressed)
+			}
+		})
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	cases := []struct {
+		name     string
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{name: "empty patterns matches everything", patterns: nil, rel: "app.log", want: true},
+		{name: "matching pattern", patterns: []string{"**/*.log"}, rel: "sub/app.log", want: true},
+		{name: "non-matching pattern", patterns: []string{"**/*.log"}, rel: "app.txt", want: false},
+		{name: "matches any of several patterns", patterns: []string{"*.txt", "**/*.log"}, rel: "sub/app.log", want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchesAny(c.patterns, c.rel); got != c.want {
+				t.Errorf("matchesAny(%v, %s) = %v, want %v", c.patterns, c.rel, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var errNoFilenameTimestamp = errors.New("filename does not match the configured timestamp pattern")
+
+// timestampOverrides carries pre-compression timestamps forward to the
+// re-walked candidate representing the resulting compressed artifact,
+// keyed by its path. This matters for RetentionModeCTime in particular:
+// compressing a file necessarily rewrites it, which bumps ctime to the
+// time of compression, so ctime alone can no longer tell us how old the
+// logical log actually is.
+type timestampOverrides map[string]time.Time
+
+// fileTimestamp resolves the timestamp a rule ages c against, per its
+// RetentionMode. Unset and unrecognized modes behave as "mtime". overrides
+// takes precedence over any mode when c.path has a recorded pre-compression
+// timestamp; pass nil when none apply.
+func fileTimestamp(c candidate, rule Rule, overrides timestampOverrides, l *log.Logger) time.Time {
+	if ts, ok := overrides[c.path]; ok {
+		return ts
+	}
+
+	switch rule.RetentionMode {
+	case RetentionModeCTime:
+		return ctimeOf(c.info)
+	case RetentionModeFilename:
+		ts, err := filenameTimestamp(c.path, rule)
+		if err != nil {
+			l.Printf("falling back to mtime for %s: %v\n", c.path, err)
+			return c.info.ModTime()
+		}
+		return ts
+	default:
+		return c.info.ModTime()
+	}
+}
+
+// filenameTimestamp extracts and parses the timestamp embedded in path's
+// base name using rule.FilenameRegexp and rule.FilenameLayout, e.g. turning
+// "program.host.user.log.INFO.20130806-151006.10530" with layout
+// "20060102-150405" into the corresponding time.Time.
+func filenameTimestamp(path string, rule Rule) (time.Time, error) {
+	re, err := regexp.Compile(rule.FilenameRegexp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	m := re.FindStringSubmatch(filepath.Base(path))
+	if len(m) < 2 {
+		return time.Time{}, errNoFilenameTimestamp
+	}
+	return time.Parse(rule.FilenameLayout, m[1])
+}
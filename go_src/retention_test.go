@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilenameTimestamp(t *testing.T) {
+	rule := Rule{
+		FilenameRegexp: `\.(\d{8}-\d{6})\.\d+$`,
+		FilenameLayout: "20060102-150405",
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		rule    Rule
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "matches and parses",
+			path: "program.host.user.log.INFO.20130806-151006.10530",
+			rule: rule,
+			want: time.Date(2013, 8, 6, 15, 10, 6, 0, time.UTC),
+		},
+		{
+			name:    "regexp does not match the filename",
+			path:    "app.log",
+			rule:    rule,
+			wantErr: true,
+		},
+		{
+			name:    "invalid regexp",
+			rule:    Rule{FilenameRegexp: `(`, FilenameLayout: "20060102-150405"},
+			path:    "app.log",
+			wantErr: true,
+		},
+		{
+			name:    "regexp matches but capture doesn't fit the layout",
+			path:    "app.20130806.log",
+			rule:    Rule{FilenameRegexp: `(\d{8})`, FilenameLayout: "20060102-150405"},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := filenameTimestamp(c.path, c.rule)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %s", c.path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filenameTimestamp(%s): %v", c.path, err)
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("filenameTimestamp(%s) = %v, want %v", c.path, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFileTimestamp_ModeSelection(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := discardLogger()
+
+	c := testCandidate(t, dir, "program.host.user.log.INFO.20130806-151006.10530", 10, mtime)
+
+	t.Run("default mode uses mtime", func(t *testing.T) {
+		got := fileTimestamp(c, Rule{}, nil, l)
+		if !got.Equal(mtime) {
+			t.Errorf("fileTimestamp = %v, want mtime %v", got, mtime)
+		}
+	})
+
+	t.Run("ctime mode uses ctimeOf", func(t *testing.T) {
+		rule := Rule{RetentionMode: RetentionModeCTime}
+		got := fileTimestamp(c, rule, nil, l)
+		if !got.Equal(ctimeOf(c.info)) {
+			t.Errorf("fileTimestamp = %v, want ctime %v", got, ctimeOf(c.info))
+		}
+	})
+
+	t.Run("filename mode parses the embedded timestamp", func(t *testing.T) {
+		rule := Rule{
+			RetentionMode:  RetentionModeFilename,
+			FilenameRegexp: `\.(\d{8}-\d{6})\.\d+$`,
+			FilenameLayout: "20060102-150405",
+		}
+		want := time.Date(2013, 8, 6, 15, 10, 6, 0, time.UTC)
+		got := fileTimestamp(c, rule, nil, l)
+		if !got.Equal(want) {
+			t.Errorf("fileTimestamp = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("filename mode falls back to mtime on parse failure", func(t *testing.T) {
+		rule := Rule{
+			RetentionMode:  RetentionModeFilename,
+			FilenameRegexp: `nomatch`,
+			FilenameLayout: "20060102-150405",
+		}
+		got := fileTimestamp(c, rule, nil, l)
+		if !got.Equal(mtime) {
+			t.Errorf("fileTimestamp = %v, want mtime fallback %v", got, mtime)
+		}
+	})
+
+	t.Run("override takes precedence over any mode", func(t *testing.T) {
+		override := time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)
+		overrides := timestampOverrides{c.path: override}
+		got := fileTimestamp(c, Rule{RetentionMode: RetentionModeCTime}, overrides, l)
+		if !got.Equal(override) {
+			t.Errorf("fileTimestamp = %v, want override %v", got, override)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultWorkers bounds deletion concurrency when a Config leaves Workers
+// unset, so a busy log tree can't fan out one goroutine per file.
+const defaultWorkers = 8
+
+// Report summarizes the outcome of a Run so callers embedding this package
+// can inspect or log it themselves instead of parsing stdout.
+type Report struct {
+	FilesScanned int
+	FilesDeleted int
+	BytesFreed   int64
+	Errors       []error
+}
+
+// Run executes every rule in cfg and returns a Report plus the aggregate of
+// any per-rule or per-file errors, joined with errors.Join.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	l := log.Default()
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	var report Report
+	now := time.Now()
+	for _, rule := range cfg.Rules {
+		if err := ctx.Err(); err != nil {
+			report.Errors = append(report.Errors, err)
+			break
+		}
+
+		resolvedRoot, err := resolveRoot(rule.Root, cfg.AllowedRoots, cfg.MinRootDepth)
+		if err != nil {
+			report.Errors = append(report.Errors, err)
+			continue
+		}
+		rule.Root = resolvedRoot
+
+		found, err := findLogFiles(rule, l)
+		if err != nil {
+			report.Errors = append(report.Errors, err)
+			continue
+		}
+		report.FilesScanned += len(found)
+		l.Printf("found %d files under %s\n", len(found), rule.Root)
+
+		var overrides timestampOverrides
+		if rule.CompressAfter.Duration > 0 {
+			var err error
+			overrides, err = compressTier(rule, found, now, l)
+			if err != nil {
+				report.Errors = append(report.Errors, err)
+			}
+			// Compression renames files on disk, so re-walk to pick up the
+			// compressed artifacts before selecting what to delete.
+			found, err = findLogFiles(rule, l)
+			if err != nil {
+				report.Errors = append(report.Errors, err)
+				continue
+			}
+		}
+
+		toDelete := selectForDeletion(found, rule, now, overrides, l)
+		if len(toDelete) == 0 {
+			continue
+		}
+		l.Printf("%d files will be deleted from %s\n", len(toDelete), rule.Root)
+
+		deleted, freed, errs := deleteConcurrently(toDelete, rule.Root, rule.DryRun, rule.FollowSymlinks, workers, l)
+		report.FilesDeleted += deleted
+		report.BytesFreed += freed
+		report.Errors = append(report.Errors, errs...)
+	}
+
+	return report, errors.Join(report.Errors...)
+}
+
+// deleteConcurrently removes candidates using a fixed-size pool of workers
+// instead of one goroutine per file.
+func deleteConcurrently(candidates []candidate, root string, dryRun, followSymlinks bool, workers int, l *log.Logger) (deleted int, bytesFreed int64, errs []error) {
+	jobs := make(chan candidate)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if err := removeFile(c.path, root, dryRun, followSymlinks, l); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					continue
+				}
+				mu.Lock()
+				deleted++
+				bytesFreed += c.info.Size()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, c := range candidates {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
+	return deleted, bytesFreed, errs
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMinRootDepth is the shallowest a resolved root may be (after
+// Config.MinRootDepth defaults in) when no narrower value is configured,
+// chosen to reject roots like "/" or "/var" outright.
+const defaultMinRootDepth = 3
+
+// resolveRoot resolves rule.Root to an absolute, symlink-free path and
+// refuses to hand back anything dangerous: "/", "$HOME", a path shallower
+// than minDepth, or a path outside every entry in allowedRoots.
+func resolveRoot(root string, allowedRoots []string, minDepth int) (string, error) {
+	if minDepth <= 0 {
+		minDepth = defaultMinRootDepth
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("resolving root %s: %w", root, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("resolving symlinks for root %s: %w", root, err)
+	}
+	resolved = filepath.Clean(resolved)
+
+	if resolved == string(filepath.Separator) {
+		return "", fmt.Errorf("refusing to use %s as a root", resolved)
+	}
+	if home, err := os.UserHomeDir(); err == nil && resolved == filepath.Clean(home) {
+		return "", fmt.Errorf("refusing to use home directory %s as a root", resolved)
+	}
+	if depth := rootDepth(resolved); depth < minDepth {
+		return "", fmt.Errorf("root %s has depth %d, below the minimum of %d", resolved, depth, minDepth)
+	}
+
+	if len(allowedRoots) == 0 {
+		return resolved, nil
+	}
+	for _, allowed := range allowedRoots {
+		allowedAbs, err := filepath.Abs(allowed)
+		if err != nil {
+			continue
+		}
+		allowedResolved, err := filepath.EvalSymlinks(allowedAbs)
+		if err != nil {
+			continue
+		}
+		if withinRoot(filepath.Clean(allowedResolved), resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("root %s is not under any allowed root", resolved)
+}
+
+// rootDepth counts the path separators in a cleaned absolute path.
+func rootDepth(p string) int {
+	trimmed := strings.Trim(p, string(filepath.Separator))
+	if trimmed == "" {
+		return 0
+	}
+	return len(strings.Split(trimmed, string(filepath.Separator)))
+}
+
+// withinRoot reports whether path is root itself or lives under it, using
+// a prefix check on their cleaned forms.
+func withinRoot(root, path string) bool {
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// confinedToRoot reports whether path, once symlinks are resolved, is
+// actually contained in root. Called right before any destructive
+// operation so a symlink swapped in after the walk can't redirect it
+// outside the intended tree.
+//
+// followSymlinks must be the same flag the walk used to reach path. With
+// it set, a rule has explicitly opted into operating through symlinked
+// directories that may resolve outside root, so only "path still resolves
+// to something real" is checked; the containment requirement is left to
+// the default (false) case, which is the one every rule gets unless it
+// asks for this.
+func confinedToRoot(root, path string, followSymlinks bool) bool {
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	if followSymlinks {
+		return true
+	}
+	return withinRoot(root, filepath.Clean(resolvedPath))
+}
+
+// loadIgnorePatterns reads dir's .housekeepignore file, if any, and returns
+// its doublestar patterns (blank lines and "#" comments skipped).
+func loadIgnorePatterns(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".housekeepignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// ignoredByAncestors reports whether path is matched by a .housekeepignore
+// pattern in its own directory or any ancestor directory up to (and
+// including) root.
+func ignoredByAncestors(path string, ignores map[string][]string, root string) bool {
+	dir := filepath.Dir(path)
+	for {
+		if patterns, ok := ignores[dir]; ok {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil && matchesAny(patterns, rel) {
+				return true
+			}
+		}
+		if dir == root {
+			return false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfinedToRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	inside := filepath.Join(root, "app.log")
+	if err := os.WriteFile(inside, nil, 0o644); err != nil {
+		t.Fatalf("writing %s: %v", inside, err)
+	}
+	if !confinedToRoot(root, inside, false) {
+		t.Errorf("expected %s to be confined to %s", inside, root)
+	}
+
+	escape := filepath.Join(root, "escape.log")
+	if err := os.Symlink(filepath.Join(outside, "secret"), escape); err != nil {
+		t.Fatalf("creating symlink: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), nil, 0o644); err != nil {
+		t.Fatalf("writing symlink target: %v", err)
+	}
+	if confinedToRoot(root, escape, false) {
+		t.Errorf("expected symlink escaping %s to be rejected", root)
+	}
+
+	if confinedToRoot(root, filepath.Join(root, "missing.log"), false) {
+		t.Errorf("expected a nonexistent path to be rejected, not silently confined")
+	}
+	if confinedToRoot(root, filepath.Join(root, "missing.log"), true) {
+		t.Errorf("expected a nonexistent path to be rejected even with followSymlinks set")
+	}
+
+	if !confinedToRoot(root, escape, true) {
+		t.Errorf("expected a symlink escaping %s to be allowed when followSymlinks is set", root)
+	}
+}
+
+func TestResolveRoot_RejectsSlash(t *testing.T) {
+	if _, err := resolveRoot("/", nil, 1); err == nil {
+		t.Error("expected an error resolving \"/\" as a root")
+	}
+}
+
+func TestResolveRoot_RejectsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if _, err := resolveRoot(home, nil, 1); err == nil {
+		t.Error("expected an error resolving $HOME as a root")
+	}
+}
+
+func TestResolveRoot_RejectsShallowPath(t *testing.T) {
+	root := t.TempDir()
+
+	if _, err := resolveRoot(root, nil, 100); err == nil {
+		t.Error("expected an error resolving a root shallower than min_root_depth")
+	}
+	if _, err := resolveRoot(root, nil, 1); err != nil {
+		t.Errorf("expected a sufficiently deep root to resolve, got %v", err)
+	}
+}
+
+func TestResolveRoot_AllowedRoots(t *testing.T) {
+	parent := t.TempDir()
+	allowed := filepath.Join(parent, "allowed")
+	if err := os.Mkdir(allowed, 0o755); err != nil {
+		t.Fatalf("creating %s: %v", allowed, err)
+	}
+	nested := filepath.Join(allowed, "nested")
+	if err := os.Mkdir(nested, 0o755); err != nil {
+		t.Fatalf("creating %s: %v", nested, err)
+	}
+	elsewhere := t.TempDir()
+
+	if _, err := resolveRoot(nested, []string{allowed}, 1); err != nil {
+		t.Errorf("expected root under an allowed root to resolve, got %v", err)
+	}
+	if _, err := resolveRoot(elsewhere, []string{allowed}, 1); err == nil {
+		t.Error("expected an error resolving a root outside every allowed root")
+	}
+}
+
+func TestIgnoredByAncestors(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("creating %s: %v", sub, err)
+	}
+
+	ignores := map[string][]string{
+		root: {"*.tmp"},
+		sub:  {"keep-*.log"},
+	}
+
+	cases := []struct {
+		path    string
+		ignored bool
+	}{
+		{filepath.Join(root, "app.log"), false},
+		{filepath.Join(root, "scratch.tmp"), true},
+		{filepath.Join(sub, "keep-me.log"), true},
+		// app.log in sub matches neither root's "*.tmp" (against "sub/app.log")
+		// nor sub's "keep-*.log", so it is not ignored.
+		{filepath.Join(sub, "app.log"), false},
+	}
+
+	for _, c := range cases {
+		if got := ignoredByAncestors(c.path, ignores, root); got != c.ignored {
+			t.Errorf("ignoredByAncestors(%s) = %v, want %v", c.path, got, c.ignored)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// ctimeOf returns the inode change time for info, falling back to the
+// modification time if the underlying stat type isn't available.
+func ctimeOf(info fs.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(stat.Ctimespec.Sec, stat.Ctimespec.Nsec)
+}
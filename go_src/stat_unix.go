@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser reports whether info's file belongs to the user
+// running this process, so rules with owner: true never touch files
+// owned by other accounts.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return int(stat.Uid) == os.Getuid()
+}